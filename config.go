@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the name of the release configuration file, expected
+// at the root of the repository.
+const configFile = ".release.yml"
+
+// allGroupsPrefix is the special tag prefix that fans out to every
+// configured group at the given version, e.g. "all/v1.2.3".
+const allGroupsPrefix = "all"
+
+// releaseConfig is the top-level shape of .release.yml. It declares
+// the artifact groups that can be released from this repository.
+type releaseConfig struct {
+	Groups []releaseGroupConfig `yaml:"groups"`
+}
+
+// releaseGroupConfig describes one independently-versioned artifact: where
+// its files live, which tag prefix publishes it, and which hooks to
+// run around the release.
+type releaseGroupConfig struct {
+	Name      string       `yaml:"name"`
+	Dir       string       `yaml:"dir"`
+	TagPrefix string       `yaml:"tag_prefix"`
+	Files     []string     `yaml:"files"`
+	Hooks     releaseHooks `yaml:"hooks"`
+}
+
+// releaseHooks are shell command lines run before and after a group
+// is released.
+type releaseHooks struct {
+	Pre  []string `yaml:"pre"`
+	Post []string `yaml:"post"`
+}
+
+// loadConfig reads and parses the release configuration at path.
+func loadConfig(path string) (*releaseConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var cfg releaseConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+
+	for i, group := range cfg.Groups {
+		if group.Name == "" {
+			return nil, fmt.Errorf("group %d: missing name", i)
+		}
+		if group.Dir == "" {
+			return nil, fmt.Errorf("group %q: missing dir", group.Name)
+		}
+		if group.TagPrefix == "" {
+			return nil, fmt.Errorf("group %q: missing tag_prefix", group.Name)
+		}
+		if group.TagPrefix == allGroupsPrefix {
+			return nil, fmt.Errorf("group %q: tag_prefix %q is reserved", group.Name, allGroupsPrefix)
+		}
+		if len(group.Files) == 0 {
+			cfg.Groups[i].Files = []string{"*"}
+		}
+	}
+
+	return &cfg, nil
+}
+
+// groupsForPrefix returns the groups that should be released for the
+// given tag prefix. The special prefix "all" selects every configured
+// group.
+func (c *releaseConfig) groupsForPrefix(prefix string) ([]releaseGroupConfig, error) {
+	if prefix == allGroupsPrefix {
+		if len(c.Groups) == 0 {
+			return nil, fmt.Errorf("no groups configured")
+		}
+		return c.Groups, nil
+	}
+
+	var groups []releaseGroupConfig
+	for _, group := range c.Groups {
+		if group.TagPrefix == prefix {
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no group matches tag prefix %q", prefix)
+	}
+
+	return groups, nil
+}
+
+// globFiles returns the regular files under dir matching any of
+// patterns, sorted and deduplicated.
+func globFiles(dir string, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("glob %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("stat %q: %w", match, err)
+			}
+			if info.IsDir() {
+				continue
+			}
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+			files = append(files, match)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// runHooks runs each shell command line in order, stopping at the
+// first failure.
+func runHooks(cmds []string) error {
+	for _, cmd := range cmds {
+		c := exec.Command("sh", "-c", cmd)
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("run hook %q: %w", cmd, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), configFile)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		path := writeConfig(t, `
+groups:
+  - name: checktypes
+    dir: checktypes
+    tag_prefix: checktypes
+`)
+		cfg, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("loadConfig() = %v, want nil", err)
+		}
+		want := []releaseGroupConfig{
+			{Name: "checktypes", Dir: "checktypes", TagPrefix: "checktypes", Files: []string{"*"}},
+		}
+		if !reflect.DeepEqual(cfg.Groups, want) {
+			t.Errorf("Groups = %#v, want %#v", cfg.Groups, want)
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		path := writeConfig(t, `
+groups:
+  - dir: checktypes
+    tag_prefix: checktypes
+`)
+		if _, err := loadConfig(path); err == nil {
+			t.Error("loadConfig() = nil, want error")
+		}
+	})
+
+	t.Run("missing dir", func(t *testing.T) {
+		path := writeConfig(t, `
+groups:
+  - name: checktypes
+    tag_prefix: checktypes
+`)
+		if _, err := loadConfig(path); err == nil {
+			t.Error("loadConfig() = nil, want error")
+		}
+	})
+
+	t.Run("missing tag_prefix", func(t *testing.T) {
+		path := writeConfig(t, `
+groups:
+  - name: checktypes
+    dir: checktypes
+`)
+		if _, err := loadConfig(path); err == nil {
+			t.Error("loadConfig() = nil, want error")
+		}
+	})
+
+	t.Run("reserved tag_prefix", func(t *testing.T) {
+		path := writeConfig(t, `
+groups:
+  - name: checktypes
+    dir: checktypes
+    tag_prefix: all
+`)
+		if _, err := loadConfig(path); err == nil {
+			t.Error("loadConfig() = nil, want error")
+		}
+	})
+}
+
+func TestGroupsForPrefix(t *testing.T) {
+	cfg := &releaseConfig{
+		Groups: []releaseGroupConfig{
+			{Name: "checktypes", Dir: "checktypes", TagPrefix: "checktypes"},
+			{Name: "exposure", Dir: "exposure", TagPrefix: "exposure"},
+		},
+	}
+
+	t.Run("all fans out to every group", func(t *testing.T) {
+		groups, err := cfg.groupsForPrefix("all")
+		if err != nil {
+			t.Fatalf("groupsForPrefix() = %v, want nil", err)
+		}
+		if !reflect.DeepEqual(groups, cfg.Groups) {
+			t.Errorf("groups = %#v, want %#v", groups, cfg.Groups)
+		}
+	})
+
+	t.Run("matching prefix", func(t *testing.T) {
+		groups, err := cfg.groupsForPrefix("exposure")
+		if err != nil {
+			t.Fatalf("groupsForPrefix() = %v, want nil", err)
+		}
+		want := []releaseGroupConfig{cfg.Groups[1]}
+		if !reflect.DeepEqual(groups, want) {
+			t.Errorf("groups = %#v, want %#v", groups, want)
+		}
+	})
+
+	t.Run("no group matches", func(t *testing.T) {
+		if _, err := cfg.groupsForPrefix("nope"); err == nil {
+			t.Error("groupsForPrefix() = nil, want error")
+		}
+	})
+}
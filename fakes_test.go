@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// fakeGitRepo is an in-memory GitRepo used to exercise the release
+// pipeline without a real git repository.
+type fakeGitRepo struct {
+	refs       map[string]string    // ref -> hash
+	dates      map[string]time.Time // hash -> committer date
+	ancestors  map[string]bool      // "ancestor:descendant" -> is ancestor
+	subtrees   map[string]string    // "hash:dir" -> tree hash
+	parentTags map[string]string    // "pattern:hash" -> tag
+	remote     string
+}
+
+func (r *fakeGitRepo) ResolveHash(ref string) (string, error) {
+	hash, ok := r.refs[ref]
+	if !ok {
+		return "", errRefNotFound
+	}
+	return hash, nil
+}
+
+func (r *fakeGitRepo) CommitDate(hash string) (time.Time, error) {
+	date, ok := r.dates[hash]
+	if !ok {
+		return time.Time{}, fmt.Errorf("no committer date for %q", hash)
+	}
+	return date, nil
+}
+
+func (r *fakeGitRepo) IsAncestor(ancestor, descendant string) (bool, error) {
+	return r.ancestors[ancestor+":"+descendant], nil
+}
+
+func (r *fakeGitRepo) SubtreeHash(hash, dir string) (string, error) {
+	tree, ok := r.subtrees[hash+":"+dir]
+	if !ok {
+		return "", fmt.Errorf("no subtree hash for %q:%q", hash, dir)
+	}
+	return tree, nil
+}
+
+func (r *fakeGitRepo) ParentTag(pattern, hash string) (string, error) {
+	return r.parentTags[pattern+":"+hash], nil
+}
+
+func (r *fakeGitRepo) RemoteURL(name string) (string, error) {
+	if r.remote == "" {
+		return "", fmt.Errorf("no remote %q", name)
+	}
+	return r.remote, nil
+}
+
+// releaseCall records a single CreateRelease invocation against
+// fakeReleaser.
+type releaseCall struct {
+	tag    string
+	target string
+	files  []string
+}
+
+// fakeReleaser is an in-memory Releaser used to exercise the release
+// pipeline without talking to the GitHub API.
+type fakeReleaser struct {
+	existing map[string]bool
+	created  []releaseCall
+	deleted  []string
+}
+
+func (f *fakeReleaser) ReleaseExists(ctx context.Context, tag string) (bool, error) {
+	return f.existing[tag], nil
+}
+
+func (f *fakeReleaser) DeleteRelease(ctx context.Context, tag string) error {
+	f.deleted = append(f.deleted, tag)
+	delete(f.existing, tag)
+	return nil
+}
+
+func (f *fakeReleaser) CreateRelease(ctx context.Context, tag, target string, files []string) error {
+	if f.existing == nil {
+		f.existing = make(map[string]bool)
+	}
+	f.existing[tag] = true
+	f.created = append(f.created, releaseCall{tag: tag, target: target, files: files})
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// GitRepo is the subset of git operations release needs to resolve
+// refs, inspect commits and check ancestry. It is implemented by
+// goGitRepo against a real repository, and faked in tests.
+type GitRepo interface {
+	// ResolveHash returns the commit hash ref resolves to. It returns
+	// errRefNotFound if ref does not exist.
+	ResolveHash(ref string) (string, error)
+	// CommitDate returns the committer date of hash.
+	CommitDate(hash string) (time.Time, error)
+	// IsAncestor reports whether ancestor is an ancestor of (or equal
+	// to) descendant.
+	IsAncestor(ancestor, descendant string) (bool, error)
+	// SubtreeHash returns the tree hash of dir as of hash.
+	SubtreeHash(hash, dir string) (string, error)
+	// ParentTag returns the most recent tag matching pattern that is
+	// an ancestor of hash's first parent, or "" if there is none.
+	ParentTag(pattern, hash string) (string, error)
+	// RemoteURL returns the URL configured for the given remote.
+	RemoteURL(name string) (string, error)
+}
+
+// goGitRepo implements GitRepo on top of go-git, without shelling out
+// to the git binary.
+type goGitRepo struct {
+	repo *git.Repository
+}
+
+// openGitRepo opens the git repository containing path.
+func openGitRepo(path string) (*goGitRepo, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("open repo: %w", err)
+	}
+	return &goGitRepo{repo: repo}, nil
+}
+
+func (r *goGitRepo) ResolveHash(ref string) (string, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return "", errRefNotFound
+		}
+		return "", fmt.Errorf("resolve revision: %w", err)
+	}
+	return hash.String(), nil
+}
+
+func (r *goGitRepo) CommitDate(hash string) (time.Time, error) {
+	commit, err := r.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("commit object: %w", err)
+	}
+	return commit.Committer.When, nil
+}
+
+func (r *goGitRepo) IsAncestor(ancestor, descendant string) (bool, error) {
+	ancestorCommit, err := r.repo.CommitObject(plumbing.NewHash(ancestor))
+	if err != nil {
+		return false, fmt.Errorf("commit object: %w", err)
+	}
+	descendantCommit, err := r.repo.CommitObject(plumbing.NewHash(descendant))
+	if err != nil {
+		return false, fmt.Errorf("commit object: %w", err)
+	}
+	ok, err := ancestorCommit.IsAncestor(descendantCommit)
+	if err != nil {
+		return false, fmt.Errorf("is ancestor: %w", err)
+	}
+	return ok, nil
+}
+
+func (r *goGitRepo) SubtreeHash(hash, dir string) (string, error) {
+	commit, err := r.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return "", fmt.Errorf("commit object: %w", err)
+	}
+	root, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("commit tree: %w", err)
+	}
+	subtree, err := root.Tree(dir)
+	if err != nil {
+		return "", fmt.Errorf("subtree %q: %w", dir, err)
+	}
+	return subtree.Hash.String(), nil
+}
+
+func (r *goGitRepo) ParentTag(pattern, hash string) (string, error) {
+	commit, err := r.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return "", fmt.Errorf("commit object: %w", err)
+	}
+	if len(commit.ParentHashes) == 0 {
+		return "", nil
+	}
+
+	tagsByCommit, err := r.tagsByCommit(pattern)
+	if err != nil {
+		return "", fmt.Errorf("list tags: %w", err)
+	}
+	if len(tagsByCommit) == 0 {
+		return "", nil
+	}
+
+	iter, err := r.repo.Log(&git.LogOptions{From: commit.ParentHashes[0]})
+	if err != nil {
+		return "", fmt.Errorf("log: %w", err)
+	}
+	defer iter.Close()
+
+	var tag string
+	err = iter.ForEach(func(c *object.Commit) error {
+		if t, ok := tagsByCommit[c.Hash]; ok {
+			tag = t
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("walk log: %w", err)
+	}
+
+	return tag, nil
+}
+
+// tagsByCommit returns the tag names matching pattern, keyed by the
+// commit hash they resolve to.
+func (r *goGitRepo) tagsByCommit(pattern string) (map[plumbing.Hash]string, error) {
+	refs, err := r.repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	defer refs.Close()
+
+	tags := make(map[plumbing.Hash]string)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		matched, err := filepath.Match(pattern, name)
+		if err != nil {
+			return fmt.Errorf("match %q: %w", pattern, err)
+		}
+		if !matched {
+			return nil
+		}
+
+		hash := ref.Hash()
+		if tagObj, err := r.repo.TagObject(hash); err == nil {
+			hash = tagObj.Target
+		}
+		commit, err := r.repo.CommitObject(hash)
+		if err != nil {
+			return nil
+		}
+		tags[commit.Hash] = name
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (r *goGitRepo) RemoteURL(name string) (string, error) {
+	remote, err := r.repo.Remote(name)
+	if err != nil {
+		return "", fmt.Errorf("get remote: %w", err)
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", fmt.Errorf("remote %q has no URL", name)
+	}
+	return urls[0], nil
+}
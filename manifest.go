@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// toolVersion identifies the version of this tool that produced a
+// release, and is recorded in the origin manifest. It is meant to be
+// overridden at build time, e.g. via
+//
+//	go build -ldflags "-X main.toolVersion=v1.2.3"
+var toolVersion = "dev"
+
+// originManifest describes the provenance of a release: enough VCS
+// metadata for downstream consumers to verify that a tag resolves to
+// the subtree they expect, and to detect if a floating alias was
+// moved without refetching the repository.
+type originManifest struct {
+	Remote      string            `json:"remote"`
+	Commit      string            `json:"commit"`
+	CommitDate  time.Time         `json:"commit_date"`
+	ParentTag   string            `json:"parent_tag,omitempty"`
+	SubtreeHash string            `json:"subtree_hash"`
+	Files       map[string]string `json:"files"`
+	ToolVersion string            `json:"tool_version"`
+}
+
+// buildOriginManifest gathers the provenance of the release being
+// published for dir at the given commit hash, covering files. tagPrefix
+// is the group's tag prefix (which may differ from dir) and is used to
+// search for the release's parent tag.
+func buildOriginManifest(repo GitRepo, dir, tagPrefix, hash string, files []string) (*originManifest, error) {
+	remote, err := repo.RemoteURL("origin")
+	if err != nil {
+		return nil, fmt.Errorf("get remote url: %w", err)
+	}
+
+	date, err := repo.CommitDate(hash)
+	if err != nil {
+		return nil, fmt.Errorf("get committer date: %w", err)
+	}
+
+	parent, err := repo.ParentTag(tagPrefix+"/v*", hash)
+	if err != nil {
+		return nil, fmt.Errorf("get parent tag: %w", err)
+	}
+
+	subtree, err := repo.SubtreeHash(hash, dir)
+	if err != nil {
+		return nil, fmt.Errorf("get subtree hash: %w", err)
+	}
+
+	sums, err := fileChecksums(files)
+	if err != nil {
+		return nil, fmt.Errorf("checksum files: %w", err)
+	}
+
+	return &originManifest{
+		Remote:      remote,
+		Commit:      hash,
+		CommitDate:  date,
+		ParentTag:   parent,
+		SubtreeHash: subtree,
+		Files:       sums,
+		ToolVersion: toolVersion,
+	}, nil
+}
+
+// fileChecksums returns the hex-encoded SHA-256 digest of every file,
+// keyed by path.
+func fileChecksums(files []string) (map[string]string, error) {
+	sums := make(map[string]string, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		sum := sha256.Sum256(data)
+		sums[file] = hex.EncodeToString(sum[:])
+	}
+	return sums, nil
+}
+
+// writeManifest marshals m as indented JSON into a file named
+// "origin.json" under a fresh temporary directory, and returns its
+// path.
+func writeManifest(m *originManifest) (string, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "release-origin-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+
+	path := filepath.Join(dir, "origin.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("write manifest: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildOriginManifestUsesTagPrefixForParentTag(t *testing.T) {
+	const dir = "services/a"
+	const tagPrefix = "svca"
+	const hash = "deadbeef"
+
+	repo := &fakeGitRepo{
+		dates: map[string]time.Time{
+			hash: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		subtrees: map[string]string{
+			hash + ":" + dir: "subtreehash",
+		},
+		parentTags: map[string]string{
+			tagPrefix + "/v*:" + hash: "svca/v1.2.2",
+		},
+		remote: "https://github.com/example/example.git",
+	}
+
+	manifest, err := buildOriginManifest(repo, dir, tagPrefix, hash, nil)
+	if err != nil {
+		t.Fatalf("buildOriginManifest() = %v, want nil", err)
+	}
+	if manifest.ParentTag != "svca/v1.2.2" {
+		t.Errorf("ParentTag = %q, want %q", manifest.ParentTag, "svca/v1.2.2")
+	}
+}
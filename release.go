@@ -2,28 +2,36 @@
 Command release publishes a new GitHub release with a given set of
 files.
 
-It expects an environment variables with the name GITHUB_REF_NAME. The
-value of GITHUB_REF_NAME must be a git tag with the format
-"dir/semver" (e.g. "checktypes/v1.2.3").
+It expects environment variables named GITHUB_REF_NAME, GITHUB_TOKEN
+and GITHUB_REPOSITORY. The value of GITHUB_REF_NAME must be a git tag
+with the format "prefix/semver" (e.g. "checktypes/v1.2.3").
+GITHUB_TOKEN authenticates against the GitHub API, and GITHUB_REPOSITORY
+(in "owner/repo" form) identifies the repository to publish releases
+to.
 
-For a given tag, it creates three releases:
+The repository declares its artifact groups in a .release.yml config
+file at its root. Each group has a name, a directory, the tag prefix
+that publishes it, a set of file globs relative to that directory
+(selecting the files attached to its releases, in place of every
+regular file in dir), and optional pre/post hooks run as shell
+commands. The tag prefix "checktypes/v1.2.3" releases the group whose
+tag_prefix is "checktypes"; the special prefix "all" (e.g.
+"all/v1.2.3") releases every configured group at that version.
 
-  - dir/vMAJOR.MINOR.PATCH
-  - dir/vMAJOR.MINOR
-  - dir/vMAJOR
+For each matching group, it creates three releases:
 
-The regular files in the directory specified in the tag are attached
-to all the releases.
+  - prefix/vMAJOR.MINOR.PATCH
+  - prefix/vMAJOR.MINOR
+  - prefix/vMAJOR
 
-For instance, if the tag is "checktypes/v1.2.3", the following
-releases would be created:
+For instance, if the tag is "checktypes/v1.2.3" and the "checktypes"
+group's files match "checktypes/*", the following releases would be
+created:
 
   - checktypes/v1.2.3 (unique)
   - checktypes/v1.2 (updated if it already exists)
   - checktypes/v1 (updated if it already exists)
 
-And the files "checktypes/*" would be attached to them.
-
 This release schema allows users to specify versions depending on
 their needs. In other words,
 
@@ -33,114 +41,263 @@ their needs. In other words,
   - v0.2.3  :=  ==v0.2.3
   - v0.2    :=  >=v0.2.0, <v0.3.0
   - v0      :=  >=v0.0.0, <v1.0.0
+
+Prerelease versions (e.g. "v1.2.3-rc.1") only ever publish their exact
+tag; the vMAJOR and vMAJOR.MINOR aliases are left untouched. v0.x
+versions never move the vMAJOR alias, since v0 carries no compatibility
+guarantees. A "+incompatible" build suffix on a v2+ version also
+publishes a "v1" alias for consumers still importing the legacy path.
+
+release talks to git and GitHub entirely in-process, through the
+GitRepo and Releaser interfaces, rather than shelling out to the git
+and gh binaries.
 */
 package main
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"golang.org/x/mod/semver"
 )
 
+// errRefNotFound is returned by GitRepo.ResolveHash when the given ref
+// does not resolve to an existing tag or branch.
+var errRefNotFound = errors.New("ref not found")
+
 func main() {
 	log.SetFlags(0)
+	ctx := context.Background()
 
 	refName := os.Getenv("GITHUB_REF_NAME")
 	if refName == "" {
 		log.Fatalf("error: missing env var GITHUB_REF_NAME")
 	}
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		log.Fatalf("error: missing env var GITHUB_TOKEN")
+	}
+	owner, name, ok := strings.Cut(os.Getenv("GITHUB_REPOSITORY"), "/")
+	if !ok {
+		log.Fatalf("error: missing or invalid env var GITHUB_REPOSITORY")
+	}
 
 	parts := strings.Split(refName, "/")
 	if len(parts) != 2 {
 		log.Fatalf("error: invalid tag name: %v", refName)
 	}
-	dir := parts[0]
+	prefix := parts[0]
 	version := parts[1]
 
 	if !semver.IsValid(version) {
 		log.Fatalf("error: invalid version: %v", version)
 	}
 
-	files, err := readDir(dir)
+	cfg, err := loadConfig(configFile)
 	if err != nil {
-		log.Fatalf("error: list files: %v", err)
+		log.Fatalf("error: load config %q: %v", configFile, err)
 	}
 
-	hash, err := gitHash(refName)
+	groups, err := cfg.groupsForPrefix(prefix)
+	if err != nil {
+		log.Fatalf("error: resolve groups for %q: %v", refName, err)
+	}
+
+	repo, err := openGitRepo(".")
+	if err != nil {
+		log.Fatalf("error: open git repo: %v", err)
+	}
+	releaser := newGitHubReleaser(ctx, owner, name, token)
+
+	hash, err := repo.ResolveHash(refName)
 	if err != nil {
 		log.Fatalf("error: get hash: %v", err)
 	}
 
-	for _, arg := range []struct {
-		version string
-		delete  bool
-	}{
-		{semver.Major(version), true},
-		{semver.MajorMinor(version), true},
-		{version, false},
-	} {
-		tag := dir + "/" + arg.version
-		if err := ghRelease(tag, hash, arg.delete, files); err != nil {
-			log.Fatalf("error: create GitHub release %q: %v", tag, err)
+	for _, group := range groups {
+		if err := releaseGroup(ctx, repo, releaser, group, version, refName, hash); err != nil {
+			log.Fatalf("error: release group %q: %v", group.Name, err)
 		}
 	}
 }
 
-func gitHash(ref string) (string, error) {
-	hash, err := execCmd("git", "show-ref", "--hash", ref)
+// releaseGroup publishes the three releases for one artifact group at
+// the given version, running its pre/post hooks around them.
+func releaseGroup(ctx context.Context, repo GitRepo, releaser Releaser, group releaseGroupConfig, version, refName, hash string) error {
+	if err := runHooks(group.Hooks.Pre); err != nil {
+		return fmt.Errorf("pre hook: %w", err)
+	}
+
+	files, err := globFiles(group.Dir, group.Files)
 	if err != nil {
-		return "", fmt.Errorf("git show-ref: %w", err)
+		return fmt.Errorf("list files: %w", err)
 	}
-	return hash, nil
-}
 
-func ghRelease(tag, target string, delete bool, files []string) error {
-	if delete {
-		if _, err := execCmd("gh", "release", "delete", "--cleanup-tag", "--yes", tag); err != nil {
-			log.Printf("warn: could not delete release %q", tag)
+	exactTag := group.TagPrefix + "/" + version
+	if err := validateExactTag(ctx, repo, releaser, exactTag, refName, hash); err != nil {
+		return fmt.Errorf("validate %q: %w", exactTag, err)
+	}
+
+	manifest, err := buildOriginManifest(repo, group.Dir, group.TagPrefix, hash, files)
+	if err != nil {
+		return fmt.Errorf("build origin manifest: %w", err)
+	}
+	manifestPath, err := writeManifest(manifest)
+	if err != nil {
+		return fmt.Errorf("write origin manifest: %w", err)
+	}
+	files = append(files, manifestPath)
+
+	for _, arg := range aliasArgsForVersion(version) {
+		tag := group.TagPrefix + "/" + arg.version
+		if arg.delete {
+			if err := validateAlias(repo, tag, hash); err != nil {
+				return fmt.Errorf("validate alias %q: %w", tag, err)
+			}
+		}
+		if err := publishRelease(ctx, releaser, tag, hash, arg.delete, files); err != nil {
+			return fmt.Errorf("create GitHub release %q: %w", tag, err)
 		}
 	}
 
-	args := []string{"release", "create", "--target", target, tag}
-	args = append(args, files...)
-	if _, err := execCmd("gh", args...); err != nil {
-		return fmt.Errorf("gh release create (%#v): %w", args, err)
+	if err := runHooks(group.Hooks.Post); err != nil {
+		return fmt.Errorf("post hook: %w", err)
 	}
 
 	return nil
 }
 
-func readDir(dir string) ([]string, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, fmt.Errorf("read dir: %w", err)
+// aliasArg is one tag to publish for a release: a version (exact or
+// floating) and whether it is a floating alias that may already exist
+// and need deleting first.
+type aliasArg struct {
+	version string
+	delete  bool
+}
+
+// aliasArgsForVersion returns the tags to publish for version,
+// following Go's module version resolution rules:
+//
+//   - Prerelease versions (e.g. "v1.2.3-rc.1") publish only the exact
+//     tag; the vMAJOR and vMAJOR.MINOR aliases are never moved, since
+//     a prerelease must never become the version consumers resolve to
+//     by default.
+//   - v0.x versions update vMAJOR.MINOR but never vMAJOR, since v0
+//     provides no compatibility guarantees.
+//   - A "+incompatible" build metadata suffix on a v2+ version also
+//     publishes/updates a "v1" alias, so consumers still importing the
+//     legacy v1 path keep receiving updates.
+func aliasArgsForVersion(version string) []aliasArg {
+	if semver.Prerelease(version) != "" {
+		return []aliasArg{{version, false}}
+	}
+
+	var args []aliasArg
+	major := semver.Major(version)
+	if major != "v0" {
+		args = append(args, aliasArg{major, true})
+	}
+	args = append(args, aliasArg{semver.MajorMinor(version), true})
+
+	if semver.Build(version) == "+incompatible" && semver.Compare(major, "v2") >= 0 {
+		args = append(args, aliasArg{"v1", true})
 	}
 
-	var files []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			log.Printf("warn: skipping dir %q", entry.Name())
-			continue
+	return append(args, aliasArg{version, false})
+}
+
+// validateExactTag ensures that the exact version tag does not already
+// have a release, so a re-run never silently clobbers a previously
+// published version. When tag is itself the pushed ref (the common
+// case), it additionally requires tag to resolve to refHash. When tag
+// belongs to a group fanned out from a different pushed ref (e.g. an
+// "all/vX.Y.Z" tag releasing a "checktypes" group), tag may never have
+// been created as a ref of its own; in that case there is nothing to
+// cross-check, and only a tag that does exist is required to match
+// refHash.
+func validateExactTag(ctx context.Context, repo GitRepo, releaser Releaser, tag, refName, refHash string) error {
+	tagHash, err := repo.ResolveHash(tag)
+	switch {
+	case err == nil:
+		if tagHash != refHash {
+			return fmt.Errorf("tag %q resolves to %q, want %q (%v)", tag, tagHash, refHash, refName)
 		}
-		files = append(files, filepath.Join(dir, entry.Name()))
+	case errors.Is(err, errRefNotFound) && tag != refName:
+		// Nothing to cross-check: this group's exact tag was never
+		// pushed as its own ref.
+	default:
+		return fmt.Errorf("get hash: %w", err)
+	}
+
+	exists, err := releaser.ReleaseExists(ctx, tag)
+	if err != nil {
+		return fmt.Errorf("check release exists: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("release %q already exists", tag)
 	}
 
-	return files, nil
+	return nil
 }
 
-func execCmd(name string, arg ...string) (string, error) {
-	stderr := &bytes.Buffer{}
-	cmd := exec.Command(name, arg...)
-	cmd.Stderr = stderr
-	out, err := cmd.Output()
+// validateAlias enforces ancestry rules for a floating alias (e.g.
+// "dir/v1" or "dir/v1.2") before it is moved to newHash. It aborts
+// unless the alias does not exist yet, already points at newHash, its
+// current target is an ancestor of newHash, or its current target is
+// strictly older than newHash by committer date.
+func validateAlias(repo GitRepo, alias, newHash string) error {
+	oldHash, err := repo.ResolveHash(alias)
+	if errors.Is(err, errRefNotFound) {
+		return nil
+	}
 	if err != nil {
-		return "", fmt.Errorf("cmd output: %w: %#q", err, stderr)
+		return fmt.Errorf("get hash: %w", err)
+	}
+	if oldHash == newHash {
+		return nil
 	}
-	return strings.TrimSpace(string(out)), nil
+
+	ancestor, err := repo.IsAncestor(oldHash, newHash)
+	if err != nil {
+		return fmt.Errorf("check ancestry: %w", err)
+	}
+	if ancestor {
+		return nil
+	}
+
+	oldDate, err := repo.CommitDate(oldHash)
+	if err != nil {
+		return fmt.Errorf("get committer date of %q: %w", oldHash, err)
+	}
+	newDate, err := repo.CommitDate(newHash)
+	if err != nil {
+		return fmt.Errorf("get committer date of %q: %w", newHash, err)
+	}
+	if newDate.After(oldDate) {
+		return nil
+	}
+
+	return fmt.Errorf("refusing to move alias %q from %q to %q: not an ancestor and not newer by committer date", alias, oldHash, newHash)
+}
+
+// publishRelease creates the GitHub release for tag, targeting the
+// given commit and attaching files. If deleteFirst is set, any
+// existing release and tag are deleted first, since floating aliases
+// are recreated rather than updated in place.
+func publishRelease(ctx context.Context, releaser Releaser, tag, target string, deleteFirst bool, files []string) error {
+	if deleteFirst {
+		if err := releaser.DeleteRelease(ctx, tag); err != nil {
+			log.Printf("warn: could not delete release %q: %v", tag, err)
+		}
+	}
+
+	if err := releaser.CreateRelease(ctx, tag, target, files); err != nil {
+		return fmt.Errorf("create release: %w", err)
+	}
+
+	return nil
 }
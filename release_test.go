@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAliasArgsForVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    []aliasArg
+	}{
+		{
+			name:    "stable",
+			version: "v1.2.3",
+			want: []aliasArg{
+				{"v1", true},
+				{"v1.2", true},
+				{"v1.2.3", false},
+			},
+		},
+		{
+			name:    "v0 never moves vMAJOR",
+			version: "v0.2.3",
+			want: []aliasArg{
+				{"v0.2", true},
+				{"v0.2.3", false},
+			},
+		},
+		{
+			name:    "prerelease publishes only the exact tag",
+			version: "v1.2.3-rc.1",
+			want: []aliasArg{
+				{"v1.2.3-rc.1", false},
+			},
+		},
+		{
+			name:    "+incompatible also updates the v1 alias",
+			version: "v2.0.0+incompatible",
+			want: []aliasArg{
+				{"v2", true},
+				{"v2.0", true},
+				{"v1", true},
+				{"v2.0.0+incompatible", false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := aliasArgsForVersion(tt.version)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("aliasArgsForVersion(%q) = %#v, want %#v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAlias(t *testing.T) {
+	const oldHash = "aaaa"
+	const newHash = "bbbb"
+
+	tests := []struct {
+		name    string
+		repo    *fakeGitRepo
+		wantErr bool
+	}{
+		{
+			name: "alias does not exist yet",
+			repo: &fakeGitRepo{refs: map[string]string{}},
+		},
+		{
+			name: "alias already at newHash",
+			repo: &fakeGitRepo{refs: map[string]string{"dir/v1": newHash}},
+		},
+		{
+			name: "old target is an ancestor",
+			repo: &fakeGitRepo{
+				refs:      map[string]string{"dir/v1": oldHash},
+				ancestors: map[string]bool{oldHash + ":" + newHash: true},
+			},
+		},
+		{
+			name: "old target is not an ancestor but is strictly older",
+			repo: &fakeGitRepo{
+				refs: map[string]string{"dir/v1": oldHash},
+				dates: map[string]time.Time{
+					oldHash: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					newHash: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "old target is neither an ancestor nor older",
+			repo: &fakeGitRepo{
+				refs: map[string]string{"dir/v1": oldHash},
+				dates: map[string]time.Time{
+					oldHash: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+					newHash: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAlias(tt.repo, "dir/v1", newHash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAlias() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateExactTag(t *testing.T) {
+	const hash = "cccc"
+	ctx := context.Background()
+
+	t.Run("tag resolves to a different commit", func(t *testing.T) {
+		repo := &fakeGitRepo{refs: map[string]string{"dir/v1.2.3": "other"}}
+		releaser := &fakeReleaser{}
+		if err := validateExactTag(ctx, repo, releaser, "dir/v1.2.3", "dir/v1.2.3", hash); err == nil {
+			t.Error("validateExactTag() = nil, want error")
+		}
+	})
+
+	t.Run("release already exists", func(t *testing.T) {
+		repo := &fakeGitRepo{refs: map[string]string{"dir/v1.2.3": hash}}
+		releaser := &fakeReleaser{existing: map[string]bool{"dir/v1.2.3": true}}
+		if err := validateExactTag(ctx, repo, releaser, "dir/v1.2.3", "dir/v1.2.3", hash); err == nil {
+			t.Error("validateExactTag() = nil, want error")
+		}
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		repo := &fakeGitRepo{refs: map[string]string{"dir/v1.2.3": hash}}
+		releaser := &fakeReleaser{}
+		if err := validateExactTag(ctx, repo, releaser, "dir/v1.2.3", "dir/v1.2.3", hash); err != nil {
+			t.Errorf("validateExactTag() = %v, want nil", err)
+		}
+	})
+
+	t.Run("fanned out from a different ref has no tag of its own", func(t *testing.T) {
+		repo := &fakeGitRepo{refs: map[string]string{"all/v1.2.3": hash}}
+		releaser := &fakeReleaser{}
+		if err := validateExactTag(ctx, repo, releaser, "checktypes/v1.2.3", "all/v1.2.3", hash); err != nil {
+			t.Errorf("validateExactTag() = %v, want nil", err)
+		}
+	})
+
+	t.Run("fanned out tag exists but diverges from refHash", func(t *testing.T) {
+		repo := &fakeGitRepo{refs: map[string]string{
+			"all/v1.2.3":        hash,
+			"checktypes/v1.2.3": "other",
+		}}
+		releaser := &fakeReleaser{}
+		if err := validateExactTag(ctx, repo, releaser, "checktypes/v1.2.3", "all/v1.2.3", hash); err == nil {
+			t.Error("validateExactTag() = nil, want error")
+		}
+	})
+}
+
+func TestReleaseGroup(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "artifact.bin"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+
+	const hash = "deadbeef"
+	refName := "checktypes/v1.2.3"
+	group := releaseGroupConfig{
+		Name:      "checktypes",
+		Dir:       dir,
+		TagPrefix: "checktypes",
+		Files:     []string{"*"},
+	}
+
+	repo := &fakeGitRepo{
+		refs: map[string]string{
+			refName: hash,
+		},
+		dates: map[string]time.Time{
+			hash: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		subtrees: map[string]string{
+			hash + ":" + dir: "subtreehash",
+		},
+		remote: "https://github.com/example/example.git",
+	}
+	releaser := &fakeReleaser{existing: map[string]bool{}}
+
+	if err := releaseGroup(context.Background(), repo, releaser, group, "v1.2.3", refName, hash); err != nil {
+		t.Fatalf("releaseGroup() = %v, want nil", err)
+	}
+
+	wantTags := []string{"checktypes/v1", "checktypes/v1.2", "checktypes/v1.2.3"}
+	var gotTags []string
+	for _, call := range releaser.created {
+		gotTags = append(gotTags, call.tag)
+		if call.target != hash {
+			t.Errorf("release %q target = %q, want %q", call.tag, call.target, hash)
+		}
+
+		var hasManifest, hasArtifact bool
+		for _, f := range call.files {
+			switch filepath.Base(f) {
+			case "origin.json":
+				hasManifest = true
+			case "artifact.bin":
+				hasArtifact = true
+			}
+		}
+		if !hasManifest {
+			t.Errorf("release %q: missing origin.json asset", call.tag)
+		}
+		if !hasArtifact {
+			t.Errorf("release %q: missing artifact.bin asset", call.tag)
+		}
+	}
+	if !reflect.DeepEqual(gotTags, wantTags) {
+		t.Errorf("created releases = %v, want %v", gotTags, wantTags)
+	}
+}
+
+// TestReleaseGroupAllFanOut exercises the "all/vX.Y.Z" fan-out: the
+// pushed ref is "all/v1.2.3", not the group's own "checktypes/v1.2.3",
+// which is never created as a ref of its own.
+func TestReleaseGroupAllFanOut(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "artifact.bin"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+
+	const hash = "deadbeef"
+	refName := "all/v1.2.3"
+	group := releaseGroupConfig{
+		Name:      "checktypes",
+		Dir:       dir,
+		TagPrefix: "checktypes",
+		Files:     []string{"*"},
+	}
+
+	repo := &fakeGitRepo{
+		refs: map[string]string{
+			refName: hash,
+		},
+		dates: map[string]time.Time{
+			hash: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		subtrees: map[string]string{
+			hash + ":" + dir: "subtreehash",
+		},
+		remote: "https://github.com/example/example.git",
+	}
+	releaser := &fakeReleaser{existing: map[string]bool{}}
+
+	if err := releaseGroup(context.Background(), repo, releaser, group, "v1.2.3", refName, hash); err != nil {
+		t.Fatalf("releaseGroup() = %v, want nil", err)
+	}
+
+	wantTags := []string{"checktypes/v1", "checktypes/v1.2", "checktypes/v1.2.3"}
+	var gotTags []string
+	for _, call := range releaser.created {
+		gotTags = append(gotTags, call.tag)
+	}
+	if !reflect.DeepEqual(gotTags, wantTags) {
+		t.Errorf("created releases = %v, want %v", gotTags, wantTags)
+	}
+}
+
+func TestErrRefNotFoundIsDistinguishable(t *testing.T) {
+	repo := &fakeGitRepo{refs: map[string]string{}}
+	_, err := repo.ResolveHash("dir/v1")
+	if !errors.Is(err, errRefNotFound) {
+		t.Errorf("ResolveHash() error = %v, want errRefNotFound", err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"golang.org/x/oauth2"
+)
+
+// uploadAssetRetries is the number of attempts made to upload a
+// release asset before giving up, since individual uploads to the
+// GitHub API are prone to transient failures.
+const uploadAssetRetries = 3
+
+// retryBackoff is the delay before the (1-indexed) attempt-th retry.
+// It is a variable so tests can shorten it.
+var retryBackoff = func(attempt int) time.Duration {
+	return time.Duration(attempt) * time.Second
+}
+
+// withRetry calls fn up to attempts times, sleeping for retryBackoff
+// between tries, and returns the last error if none succeed.
+func withRetry(attempts int, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt < attempts {
+			time.Sleep(retryBackoff(attempt))
+		}
+	}
+	return fmt.Errorf("after %d attempts: %w", attempts, err)
+}
+
+// Releaser is the subset of GitHub release operations release needs.
+// It is implemented by githubReleaser, and faked in tests.
+type Releaser interface {
+	// ReleaseExists reports whether a release exists for tag.
+	ReleaseExists(ctx context.Context, tag string) (bool, error)
+	// DeleteRelease deletes the release for tag and its underlying
+	// git tag. It is a no-op if no release exists for tag.
+	DeleteRelease(ctx context.Context, tag string) error
+	// CreateRelease creates a release for tag targeting the given
+	// commit, with files attached as assets.
+	CreateRelease(ctx context.Context, tag, target string, files []string) error
+}
+
+// githubReleaser implements Releaser against the real GitHub API.
+type githubReleaser struct {
+	client *github.Client
+	owner  string
+	repo   string
+}
+
+// newGitHubReleaser builds a githubReleaser for owner/repo,
+// authenticated with token.
+func newGitHubReleaser(ctx context.Context, owner, repo, token string) *githubReleaser {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	client := github.NewClient(oauth2.NewClient(ctx, ts))
+	return &githubReleaser{client: client, owner: owner, repo: repo}
+}
+
+func (g *githubReleaser) ReleaseExists(ctx context.Context, tag string) (bool, error) {
+	_, resp, err := g.client.Repositories.GetReleaseByTag(ctx, g.owner, g.repo, tag)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get release by tag: %w", err)
+	}
+	return true, nil
+}
+
+func (g *githubReleaser) DeleteRelease(ctx context.Context, tag string) error {
+	release, resp, err := g.client.Repositories.GetReleaseByTag(ctx, g.owner, g.repo, tag)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get release by tag: %w", err)
+	}
+
+	if _, err := g.client.Repositories.DeleteRelease(ctx, g.owner, g.repo, release.GetID()); err != nil {
+		return fmt.Errorf("delete release: %w", err)
+	}
+	if _, err := g.client.Git.DeleteRef(ctx, g.owner, g.repo, "tags/"+tag); err != nil {
+		return fmt.Errorf("delete tag ref: %w", err)
+	}
+
+	return nil
+}
+
+func (g *githubReleaser) CreateRelease(ctx context.Context, tag, target string, files []string) error {
+	release, _, err := g.client.Repositories.CreateRelease(ctx, g.owner, g.repo, &github.RepositoryRelease{
+		TagName:         github.String(tag),
+		TargetCommitish: github.String(target),
+	})
+	if err != nil {
+		return fmt.Errorf("create release: %w", err)
+	}
+
+	for _, file := range files {
+		if err := g.uploadAsset(ctx, release.GetID(), file); err != nil {
+			return fmt.Errorf("upload asset %q: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// uploadAsset uploads path as a release asset, retrying on failure
+// since the upload is re-sent from the start each time (the file must
+// be reopened, as the previous attempt may have consumed its reader).
+func (g *githubReleaser) uploadAsset(ctx context.Context, releaseID int64, path string) error {
+	return withRetry(uploadAssetRetries, func() error {
+		return g.uploadAssetOnce(ctx, releaseID, path)
+	})
+}
+
+func (g *githubReleaser) uploadAssetOnce(ctx context.Context, releaseID int64, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	opts := &github.UploadOptions{Name: filepath.Base(path)}
+	if _, _, err := g.client.Repositories.UploadReleaseAsset(ctx, g.owner, g.repo, releaseID, opts, f); err != nil {
+		return fmt.Errorf("upload release asset: %w", err)
+	}
+
+	return nil
+}
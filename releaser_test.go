@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	restore := retryBackoff
+	retryBackoff = func(int) time.Duration { return 0 }
+	defer func() { retryBackoff = restore }()
+
+	t.Run("succeeds after transient failures", func(t *testing.T) {
+		errTransient := errors.New("transient failure")
+		var calls int
+		err := withRetry(3, func() error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("withRetry() = %v, want nil", err)
+		}
+		if calls != 3 {
+			t.Errorf("calls = %d, want 3", calls)
+		}
+	})
+
+	t.Run("gives up after exhausting attempts", func(t *testing.T) {
+		errPermanent := errors.New("permanent failure")
+		var calls int
+		err := withRetry(3, func() error {
+			calls++
+			return errPermanent
+		})
+		if !errors.Is(err, errPermanent) {
+			t.Errorf("withRetry() error = %v, want wrapped %v", err, errPermanent)
+		}
+		if calls != 3 {
+			t.Errorf("calls = %d, want 3", calls)
+		}
+	})
+}